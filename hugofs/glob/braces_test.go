@@ -0,0 +1,73 @@
+// Copyright 2019 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glob
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	cases := []struct {
+		pattern string
+		want    []string
+	}{
+		{"content/foo.md", []string{"content/foo.md"}},
+		{"content/{en,fr}/posts/**", []string{"content/en/posts/**", "content/fr/posts/**"}},
+		{"{a,b}/{c,d}", []string{"a/c", "a/d", "b/c", "b/d"}},
+	}
+
+	for _, c := range cases {
+		got := ExpandBraces(c.pattern)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ExpandBraces(%q) = %v, want %v", c.pattern, got, c.want)
+		}
+	}
+}
+
+func TestResolveRootDirWithBraces(t *testing.T) {
+	cases := []struct {
+		pattern string
+		want    string
+	}{
+		{"content/{en,fr}/posts/**", "content"},
+		{"assets/**.json", "assets"},
+		{"{en,fr}/posts/**", ""},
+	}
+
+	for _, c := range cases {
+		if got := ResolveRootDir(c.pattern); got != c.want {
+			t.Errorf("ResolveRootDir(%q) = %q, want %q", c.pattern, got, c.want)
+		}
+	}
+}
+
+func TestGetGlobWithBraces(t *testing.T) {
+	gc := newGlobCache(true, defaultGlobCacheMaxEntries)
+
+	g, err := gc.GetGlob("content/{en,fr}/posts/**")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !g.Match("content/en/posts/foo.md") {
+		t.Error("content/en/posts/foo.md should match")
+	}
+	if !g.Match("content/fr/posts/foo.md") {
+		t.Error("content/fr/posts/foo.md should match")
+	}
+	if g.Match("content/de/posts/foo.md") {
+		t.Error("content/de/posts/foo.md should not match")
+	}
+}
@@ -14,29 +14,29 @@
 package glob
 
 import (
+	"container/list"
 	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/gobwas/glob"
 	"github.com/gobwas/glob/syntax"
 )
 
+// defaultGlobCacheMaxEntries is the default size of a globCache's LRU. It
+// is generous enough for real-world sites, while still bounding memory use
+// for a long-running `hugo server` fed a steady stream of unique,
+// template-generated patterns.
+const defaultGlobCacheMaxEntries = 1000
+
 var (
 	isWindows        = runtime.GOOS == "windows"
-	defaultGlobCache = &globCache{
-		isCaseSensitive: false,
-		isWindows:       isWindows,
-		cache:           make(map[string]globErr),
-	}
+	defaultGlobCache = newGlobCache(false, defaultGlobCacheMaxEntries)
 
-	filenamesGlobCache = &globCache{
-		isCaseSensitive: true, // TODO(bep) bench
-		isWindows:       isWindows,
-		cache:           make(map[string]globErr),
-	}
+	filenamesGlobCache = newGlobCache(true, defaultGlobCacheMaxEntries) // TODO(bep) bench
 )
 
 type globErr struct {
@@ -44,40 +44,155 @@ type globErr struct {
 	err  error
 }
 
+// CacheStats holds a snapshot of a globCache's LRU counters.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+
+	// GlobSetSize and GlobSetEvictions are the same counters for the
+	// separate, independently bounded LRU that caches compiled GlobSets.
+	GlobSetSize      int
+	GlobSetEvictions uint64
+}
+
+type cacheEntry struct {
+	key string
+	val globErr
+}
+
+type globSetEntry struct {
+	key string
+	val *GlobSet
+}
+
 type globCache struct {
 	// Config
 	isCaseSensitive bool
 	isWindows       bool
+	maxEntries      int
 
-	// Cache
+	// Cache, an LRU keyed by pattern: ll holds *cacheEntry in
+	// most-recently-used-first order, items indexes ll's elements by key.
 	sync.RWMutex
-	cache map[string]globErr
+	ll    *list.List
+	items map[string]*list.Element
+
+	// globSets is a second, independently bounded LRU (same eviction
+	// policy, own counters) caching compiled GlobSets keyed by their
+	// joined pattern list, since those can't share the pattern cache above.
+	globSetLL    *list.List
+	globSetItems map[string]*list.Element
+
+	hits             uint64
+	misses           uint64
+	evictions        uint64
+	globSetEvictions uint64
 }
 
-func (gc *globCache) GetGlob(pattern string) (glob.Glob, error) {
-	var eg globErr
+func newGlobCache(isCaseSensitive bool, maxEntries int) *globCache {
+	return &globCache{
+		isCaseSensitive: isCaseSensitive,
+		isWindows:       isWindows,
+		maxEntries:      maxEntries,
+		ll:              list.New(),
+		items:           make(map[string]*list.Element),
+		globSetLL:       list.New(),
+		globSetItems:    make(map[string]*list.Element),
+	}
+}
 
-	gc.RLock()
-	var found bool
-	eg, found = gc.cache[pattern]
-	gc.RUnlock()
-	if found {
+// GetGlobSet returns a compiled GlobSet for patterns, reusing a previously
+// compiled set for the same (ordered) list of patterns.
+func (gc *globCache) GetGlobSet(patterns []string) (*GlobSet, error) {
+	key := strings.Join(patterns, "\x00")
+
+	gc.Lock()
+	if el, found := gc.globSetItems[key]; found {
+		gc.globSetLL.MoveToFront(el)
+		gc.Unlock()
+		return el.Value.(*globSetEntry).val, nil
+	}
+	gc.Unlock()
+
+	gs, err := NewGlobSet(gc, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	gc.Lock()
+	if el, found := gc.globSetItems[key]; found {
+		// Lost a race to compile the same set of patterns; keep the
+		// existing entry.
+		gc.globSetLL.MoveToFront(el)
+		gc.Unlock()
+		return el.Value.(*globSetEntry).val, nil
+	}
+	el := gc.globSetLL.PushFront(&globSetEntry{key: key, val: gs})
+	gc.globSetItems[key] = el
+	gc.evictGlobSetsLocked()
+	gc.Unlock()
+
+	return gs, nil
+}
+
+// evictGlobSetsLocked removes least-recently-used GlobSets until the cache
+// is back within its configured size. Callers must hold gc's write lock.
+func (gc *globCache) evictGlobSetsLocked() {
+	if gc.maxEntries <= 0 {
+		return
+	}
+	for gc.globSetLL.Len() > gc.maxEntries {
+		oldest := gc.globSetLL.Back()
+		if oldest == nil {
+			return
+		}
+		gc.globSetLL.Remove(oldest)
+		delete(gc.globSetItems, oldest.Value.(*globSetEntry).key)
+		gc.globSetEvictions++
+	}
+}
+
+func (gc *globCache) GetGlob(pattern string) (glob.Glob, error) {
+	gc.Lock()
+	if el, found := gc.items[pattern]; found {
+		gc.ll.MoveToFront(el)
+		gc.Unlock()
+		atomic.AddUint64(&gc.hits, 1)
+		eg := el.Value.(*cacheEntry).val
 		return eg.glob, eg.err
 	}
+	gc.Unlock()
+	atomic.AddUint64(&gc.misses, 1)
 
 	var g glob.Glob
 	var err error
 
-	pattern = filepath.ToSlash(pattern)
+	compilePattern := filepath.ToSlash(pattern)
 
-	if gc.isCaseSensitive {
-		g, err = glob.Compile(pattern, '/')
-	} else {
-		g, err = glob.Compile(strings.ToLower(pattern), '/')
+	if !gc.isCaseSensitive {
+		compilePattern = strings.ToLower(compilePattern)
+	}
 
+	if expansions := ExpandBraces(compilePattern); len(expansions) > 1 {
+		globs := make([]glob.Glob, 0, len(expansions))
+		for _, expansion := range expansions {
+			eg, e := glob.Compile(expansion, '/')
+			if e != nil {
+				err = e
+				break
+			}
+			globs = append(globs, eg)
+		}
+		if err == nil {
+			g = braceGlob{globs: globs}
+		}
+	} else {
+		g, err = glob.Compile(compilePattern, '/')
 	}
 
-	eg = globErr{
+	eg := globErr{
 		globDecorator{
 			g:               g,
 			isCaseSensitive: gc.isCaseSensitive,
@@ -86,12 +201,77 @@ func (gc *globCache) GetGlob(pattern string) (glob.Glob, error) {
 	}
 
 	gc.Lock()
-	gc.cache[pattern] = eg
+	if el, found := gc.items[pattern]; found {
+		// Lost a race to compile the same pattern; keep the existing entry.
+		gc.ll.MoveToFront(el)
+		gc.Unlock()
+		return el.Value.(*cacheEntry).val.glob, el.Value.(*cacheEntry).val.err
+	}
+	el := gc.ll.PushFront(&cacheEntry{key: pattern, val: eg})
+	gc.items[pattern] = el
+	gc.evictLocked()
 	gc.Unlock()
 
 	return eg.glob, eg.err
 }
 
+// evictLocked removes least-recently-used entries until the cache is back
+// within its configured size. Callers must hold gc's write lock.
+func (gc *globCache) evictLocked() {
+	if gc.maxEntries <= 0 {
+		return
+	}
+	for gc.ll.Len() > gc.maxEntries {
+		oldest := gc.ll.Back()
+		if oldest == nil {
+			return
+		}
+		gc.ll.Remove(oldest)
+		delete(gc.items, oldest.Value.(*cacheEntry).key)
+		gc.evictions++
+	}
+}
+
+// Stats returns a snapshot of the cache's hit/miss/eviction counters and
+// its current size.
+func (gc *globCache) Stats() CacheStats {
+	gc.RLock()
+	defer gc.RUnlock()
+	return CacheStats{
+		Hits:             atomic.LoadUint64(&gc.hits),
+		Misses:           atomic.LoadUint64(&gc.misses),
+		Evictions:        gc.evictions,
+		Size:             gc.ll.Len(),
+		GlobSetSize:      gc.globSetLL.Len(),
+		GlobSetEvictions: gc.globSetEvictions,
+	}
+}
+
+// Purge removes every cached glob and GlobSet whose key starts with
+// patternPrefix, or everything if patternPrefix is empty. It is meant for
+// tests and live-reload scenarios where a set of patterns is known to be
+// stale. A GlobSet's key is its patterns joined with a NUL byte, so
+// patternPrefix only usefully targets GlobSets when it matches the start
+// of their first pattern (or is empty).
+func (gc *globCache) Purge(patternPrefix string) {
+	gc.Lock()
+	defer gc.Unlock()
+
+	for key, el := range gc.items {
+		if patternPrefix == "" || strings.HasPrefix(key, patternPrefix) {
+			gc.ll.Remove(el)
+			delete(gc.items, key)
+		}
+	}
+
+	for key, el := range gc.globSetItems {
+		if patternPrefix == "" || strings.HasPrefix(key, patternPrefix) {
+			gc.globSetLL.Remove(el)
+			delete(gc.globSetItems, key)
+		}
+	}
+}
+
 type globDecorator struct {
 	// Whether both pattern and the strings to match will be matched
 	// by their original case.
@@ -123,8 +303,32 @@ func NormalizePath(p string) string {
 }
 
 // ResolveRootDir takes a normalized path on the form "assets/**.json" and
-// determines any root dir, i.e. any start path without any wildcards.
+// determines any root dir, i.e. any start path without any wildcards. A
+// brace group such as "content/{en,fr}/posts/**" is not itself a wildcard,
+// so it is expanded first and the root dir is the literal path prefix
+// shared by every expansion, e.g. "content".
 func ResolveRootDir(p string) string {
+	if !strings.Contains(p, "{") {
+		return resolveRootDirForPattern(p)
+	}
+
+	expansions := ExpandBraces(p)
+	if len(expansions) <= 1 {
+		return resolveRootDirForPattern(p)
+	}
+
+	root := resolveRootDirForPattern(expansions[0])
+	for _, expansion := range expansions[1:] {
+		root = commonPathPrefix(root, resolveRootDirForPattern(expansion))
+		if root == "" {
+			break
+		}
+	}
+
+	return root
+}
+
+func resolveRootDirForPattern(p string) string {
 	parts := strings.Split(path.Dir(p), "/")
 	var roots []string
 	for _, part := range parts {
@@ -141,6 +345,73 @@ func ResolveRootDir(p string) string {
 	return strings.Join(roots, "/")
 }
 
+// commonPathPrefix returns the longest "/"-separated path prefix shared by
+// a and b.
+func commonPathPrefix(a, b string) string {
+	as := strings.Split(a, "/")
+	bs := strings.Split(b, "/")
+
+	n := len(as)
+	if len(bs) < n {
+		n = len(bs)
+	}
+
+	var common []string
+	for i := 0; i < n; i++ {
+		if as[i] != bs[i] {
+			break
+		}
+		common = append(common, as[i])
+	}
+
+	return strings.Join(common, "/")
+}
+
+// ExpandBraces pre-expands brace alternations such as "{a,b,c}" into a
+// slice of concrete patterns, e.g. "content/{en,fr}/**" becomes
+// ["content/en/**", "content/fr/**"]. Brace groups are assumed to be
+// non-nested. A pattern without any brace group is returned unchanged as
+// a single-element slice.
+func ExpandBraces(pattern string) []string {
+	start := strings.IndexByte(pattern, '{')
+	if start == -1 {
+		return []string{pattern}
+	}
+	end := strings.IndexByte(pattern[start:], '}')
+	if end == -1 {
+		return []string{pattern}
+	}
+	end += start
+
+	prefix := pattern[:start]
+	suffix := pattern[end+1:]
+	alternatives := strings.Split(pattern[start+1:end], ",")
+
+	var expanded []string
+	for _, alt := range alternatives {
+		for _, rest := range ExpandBraces(suffix) {
+			expanded = append(expanded, prefix+alt+rest)
+		}
+	}
+
+	return expanded
+}
+
+// braceGlob matches if any of its underlying globs matches, used to
+// implement brace expansion as a single compiled glob.Glob.
+type braceGlob struct {
+	globs []glob.Glob
+}
+
+func (b braceGlob) Match(s string) bool {
+	for _, g := range b.globs {
+		if g.Match(s) {
+			return true
+		}
+	}
+	return false
+}
+
 // FilterGlobParts removes any string with glob wildcard.
 func FilterGlobParts(a []string) []string {
 	b := a[:0]
@@ -162,11 +433,102 @@ func HasGlobChar(s string) bool {
 	return false
 }
 
+// globRule is a single entry in an ordered list of gitignore-style rules,
+// where the last rule matching a given filename wins.
+type globRule struct {
+	// raw is the rule pattern with the "!" negation prefix (if any) and
+	// any trailing "/" (directory-only marker) already stripped, in its
+	// normalized (non-anchored patterns without a "/" get a "**/" prefix)
+	// slash form.
+	// It is kept around so we can resolve a static root dir for MatchDir.
+	raw string
+
+	g glob.Glob
+
+	// descendants is only set for directory-only rules ("foo/") and
+	// matches anything below the directory, since the rule itself must
+	// not match sibling files that merely share the directory's name.
+	descendants glob.Glob
+
+	negate  bool
+	dirOnly bool
+}
+
+// compileGlobRule parses and compiles a single gitignore-style rule.
+func compileGlobRule(cache *globCache, pattern string) (globRule, error) {
+	var rule globRule
+
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	}
+
+	anchored := strings.HasPrefix(pattern, "/")
+	if anchored {
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") && pattern != "/" {
+		rule.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+
+	var bareName string
+	if !anchored && !strings.Contains(pattern, "/") {
+		// A pattern with no slashes matches in any directory, just like
+		// in .gitignore, including at the root itself.
+		bareName = pattern
+		pattern = "**/" + pattern
+	}
+
+	rule.raw = pattern
+
+	g, err := cache.GetGlob(filepath.FromSlash(pattern))
+	if err != nil {
+		return rule, err
+	}
+	rule.g = g
+
+	if bareName != "" {
+		bg, err := cache.GetGlob(filepath.FromSlash(bareName))
+		if err != nil {
+			return rule, err
+		}
+		rule.g = braceGlob{globs: []glob.Glob{rule.g, bg}}
+	}
+
+	if rule.dirOnly {
+		gd, err := cache.GetGlob(filepath.FromSlash(pattern + "/**"))
+		if err != nil {
+			return rule, err
+		}
+		rule.descendants = gd
+	}
+
+	return rule, nil
+}
+
+func (r globRule) Match(filename string) bool {
+	if r.g.Match(filename) {
+		return true
+	}
+	return r.dirOnly && r.descendants.Match(filename)
+}
+
 type FilenameFilter struct {
 	shouldInclude func(filename string) bool
 	inclusions    []glob.Glob
 	exclusions    []glob.Glob
+	rules         []globRule
 	isWindows     bool
+
+	// inclusionSet and exclusionSet are compiled versions of inclusions
+	// and exclusions that dispatch by bucket (literal, extension, prefix
+	// trie, fallback) instead of testing every pattern linearly. NewFilenameFilter
+	// sets these whenever there is at least one inclusion/exclusion pattern, and
+	// Match prefers them over the plain inclusions/exclusions slices when set.
+	inclusionSet *GlobSet
+	exclusionSet *GlobSet
 }
 
 // NewFilenameFilter creates a new Glob where the Match method will
@@ -190,6 +552,47 @@ func NewFilenameFilter(inclusions, exclusions []string) (*FilenameFilter, error)
 		filter.exclusions = append(filter.exclusions, g)
 	}
 
+	if len(inclusions) > 0 {
+		gs, err := filenamesGlobCache.GetGlobSet(inclusions)
+		if err != nil {
+			return nil, err
+		}
+		filter.inclusionSet = gs
+	}
+	if len(exclusions) > 0 {
+		gs, err := filenamesGlobCache.GetGlobSet(exclusions)
+		if err != nil {
+			return nil, err
+		}
+		filter.exclusionSet = gs
+	}
+
+	return filter, nil
+}
+
+// NewFilenameFilterFromRules creates a new FilenameFilter from an ordered
+// list of gitignore-style rules. A rule prefixed with "!" negates a
+// preceding exclusion, a rule prefixed with "/" is anchored to the root
+// (it will only match there), and a rule suffixed with "/" matches that
+// path and everything below it; Match sees only a name, so a file at
+// exactly that path matches too. Rules are evaluated in order
+// and the last rule that matches a given filename decides whether it is
+// included, mirroring .gitignore semantics. A filename that matches no
+// rule at all is included.
+func NewFilenameFilterFromRules(rules []string) (*FilenameFilter, error) {
+	filter := &FilenameFilter{isWindows: isWindows}
+
+	for _, rule := range rules {
+		if rule == "" {
+			continue
+		}
+		r, err := compileGlobRule(filenamesGlobCache, rule)
+		if err != nil {
+			return nil, err
+		}
+		filter.rules = append(filter.rules, r)
+	}
+
 	return filter, nil
 }
 
@@ -222,17 +625,91 @@ func (f *FilenameFilter) Match(filename string) bool {
 
 	}
 
-	for _, inclusion := range f.inclusions {
-		if inclusion.Match(filename) {
+	if f.rules != nil {
+		include := true
+		for _, rule := range f.rules {
+			if rule.Match(filename) {
+				include = rule.negate
+			}
+		}
+		return include
+	}
+
+	if f.inclusionSet != nil {
+		if f.inclusionSet.Match(filename) {
 			return true
 		}
+	} else {
+		for _, inclusion := range f.inclusions {
+			if inclusion.Match(filename) {
+				return true
+			}
+		}
 	}
 
-	for _, exclusion := range f.exclusions {
-		if exclusion.Match(filename) {
+	if f.exclusionSet != nil {
+		if f.exclusionSet.Match(filename) {
 			return false
 		}
+	} else {
+		for _, exclusion := range f.exclusions {
+			if exclusion.Match(filename) {
+				return false
+			}
+		}
 	}
 
 	return f.inclusions == nil && f.shouldInclude == nil
 }
+
+// MatchDir returns whether a tree walker should descend into dirname.
+// It returns false only when it can be certain that no file below
+// dirname could ever match, so it is safe to use for pruning.
+func (f *FilenameFilter) MatchDir(dirname string) bool {
+	if f == nil || f.rules == nil {
+		return true
+	}
+
+	dirname = strings.Trim(filepath.ToSlash(dirname), "/")
+
+	include := true
+	for _, rule := range f.rules {
+		// Append a synthetic path segment so ResolveRootDir (which looks
+		// at path.Dir) keeps the last static segment of rule.raw too.
+		root := ResolveRootDir(rule.raw + "/_")
+		if root == "" {
+			// The rule has no static root, e.g. "*.json", so it may
+			// apply anywhere below dirname. Only a directory rule that
+			// matches dirname excludes everything below it.
+			if rule.negate {
+				include = true
+			} else if rule.dirOnly && rule.Match(dirname) {
+				include = false
+			}
+			continue
+		}
+
+		if root == dirname || strings.HasPrefix(dirname, root+"/") {
+			// dirname is at or below the rule's root. An exclusion covers
+			// dirname and everything below it only when its wildcard
+			// portion is a bare "**" (or it is a directory rule on root).
+			// Apply it directly rather than through rule.Match, since a
+			// pattern like "root/**" by design never matches "root"
+			// itself even though it governs everything inside it.
+			if rule.negate {
+				include = true
+			} else if rule.raw == root+"/**" || (rule.dirOnly && rule.raw == root) {
+				include = false
+			}
+			continue
+		}
+
+		if strings.HasPrefix(root, dirname+"/") {
+			// The rule's root is somewhere below dirname, so we
+			// cannot prune: there may be a match further down.
+			include = true
+		}
+	}
+
+	return include
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glob
+
+import (
+	"testing"
+)
+
+func TestFilenameFilterFromRulesNegation(t *testing.T) {
+	filter, err := NewFilenameFilterFromRules([]string{
+		"content/drafts/**",
+		"!content/drafts/keep/**",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.Match("content/drafts/foo.md") {
+		t.Error("content/drafts/foo.md should be excluded")
+	}
+	if !filter.Match("content/drafts/keep/foo.md") {
+		t.Error("content/drafts/keep/foo.md should be re-included")
+	}
+	if !filter.Match("content/posts/foo.md") {
+		t.Error("content/posts/foo.md should be included")
+	}
+}
+
+func TestFilenameFilterFromRulesOrderMatters(t *testing.T) {
+	// The last matching rule wins, so re-excluding after re-including
+	// should win back over the earlier negation.
+	filter, err := NewFilenameFilterFromRules([]string{
+		"content/drafts/**",
+		"!content/drafts/keep/**",
+		"content/drafts/keep/secret.md",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !filter.Match("content/drafts/keep/foo.md") {
+		t.Error("content/drafts/keep/foo.md should be re-included")
+	}
+	if filter.Match("content/drafts/keep/secret.md") {
+		t.Error("content/drafts/keep/secret.md should be excluded again by the last rule")
+	}
+}
+
+func TestFilenameFilterFromRulesAnchored(t *testing.T) {
+	filter, err := NewFilenameFilterFromRules([]string{
+		"/foo.md",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.Match("foo.md") {
+		t.Error("foo.md at the root should be excluded")
+	}
+	if !filter.Match("sub/foo.md") {
+		t.Error("sub/foo.md should not be affected by the anchored rule")
+	}
+}
+
+func TestFilenameFilterFromRulesUnanchored(t *testing.T) {
+	filter, err := NewFilenameFilterFromRules([]string{
+		"foo.md",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.Match("foo.md") {
+		t.Error("foo.md at the root should be excluded by the unanchored rule")
+	}
+	if filter.Match("sub/foo.md") {
+		t.Error("sub/foo.md should be excluded by the unanchored rule")
+	}
+}
+
+func TestFilenameFilterFromRulesDirOnly(t *testing.T) {
+	filter, err := NewFilenameFilterFromRules([]string{
+		"content/drafts/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.Match("content/drafts/foo.md") {
+		t.Error("content/drafts/foo.md should be excluded")
+	}
+	if !filter.Match("content/drafts-final/foo.md") {
+		t.Error("content/drafts-final/foo.md should not match the content/drafts/ directory rule")
+	}
+}
+
+func TestFilenameFilterMatchDir(t *testing.T) {
+	filter, err := NewFilenameFilterFromRules([]string{
+		"content/drafts/**",
+		"!content/drafts/keep/**",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A sibling directory is unaffected by the exclusion below
+	// content/drafts and should still be walked.
+	if !filter.MatchDir("content/posts") {
+		t.Error("content/posts should be walked")
+	}
+
+	// content/drafts/keep re-includes files below it, so the walker
+	// must still descend into it even though its parent is excluded.
+	if !filter.MatchDir("content/drafts/keep") {
+		t.Error("content/drafts/keep should be walked despite the exclusion on content/drafts")
+	}
+}
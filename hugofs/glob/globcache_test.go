@@ -0,0 +1,148 @@
+// Copyright 2019 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glob
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGlobCacheStatsHitsAndMisses(t *testing.T) {
+	gc := newGlobCache(false, defaultGlobCacheMaxEntries)
+
+	if _, err := gc.GetGlob("a/b/*.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gc.GetGlob("a/b/*.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := gc.Stats()
+	if stats.Misses != 1 {
+		t.Errorf("Misses = %d, want 1", stats.Misses)
+	}
+	if stats.Hits != 1 {
+		t.Errorf("Hits = %d, want 1", stats.Hits)
+	}
+	if stats.Size != 1 {
+		t.Errorf("Size = %d, want 1", stats.Size)
+	}
+}
+
+func TestGlobCacheEviction(t *testing.T) {
+	gc := newGlobCache(false, 2)
+
+	for i := 0; i < 3; i++ {
+		if _, err := gc.GetGlob(fmt.Sprintf("pattern-%d/*.json", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := gc.Stats()
+	if stats.Size != 2 {
+		t.Errorf("Size = %d, want 2", stats.Size)
+	}
+	if stats.Evictions != 1 {
+		t.Errorf("Evictions = %d, want 1", stats.Evictions)
+	}
+
+	// The oldest pattern should have been evicted, so fetching it again
+	// is a miss, not a hit.
+	if _, err := gc.GetGlob("pattern-0/*.json"); err != nil {
+		t.Fatal(err)
+	}
+	if got := gc.Stats().Misses; got != 4 {
+		t.Errorf("Misses = %d, want 4", got)
+	}
+}
+
+func TestGlobCacheGlobSetEviction(t *testing.T) {
+	gc := newGlobCache(false, 2)
+
+	for i := 0; i < 3; i++ {
+		patterns := []string{fmt.Sprintf("pattern-%d/**", i)}
+		if _, err := gc.GetGlobSet(patterns); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := gc.Stats()
+	if stats.GlobSetSize != 2 {
+		t.Errorf("GlobSetSize = %d, want 2", stats.GlobSetSize)
+	}
+	if stats.GlobSetEvictions != 1 {
+		t.Errorf("GlobSetEvictions = %d, want 1", stats.GlobSetEvictions)
+	}
+}
+
+func TestGlobCachePurge(t *testing.T) {
+	gc := newGlobCache(false, defaultGlobCacheMaxEntries)
+
+	if _, err := gc.GetGlob("content/a/*.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gc.GetGlob("content/b/*.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gc.GetGlob("static/c/*.json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gc.GetGlobSet([]string{"content/a/*.json", "content/b/*.json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	gc.Purge("content/")
+
+	if got := gc.Stats().Size; got != 1 {
+		t.Errorf("Size after Purge = %d, want 1", got)
+	}
+	if got := gc.Stats().GlobSetSize; got != 0 {
+		t.Errorf("GlobSetSize after Purge = %d, want 0", got)
+	}
+
+	// Purging again is a no-op on the pattern that remains.
+	gc.Purge("content/")
+	if got := gc.Stats().Size; got != 1 {
+		t.Errorf("Size after second Purge = %d, want 1", got)
+	}
+
+	gc.Purge("")
+	if got := gc.Stats().Size; got != 0 {
+		t.Errorf("Size after Purge(\"\") = %d, want 0", got)
+	}
+}
+
+func TestGlobCacheSharedEvictionPolicyIndependentStats(t *testing.T) {
+	if defaultGlobCache.maxEntries != filenamesGlobCache.maxEntries {
+		t.Errorf("maxEntries = %d and %d, want the same", defaultGlobCache.maxEntries, filenamesGlobCache.maxEntries)
+	}
+
+	before := filenamesGlobCache.Stats()
+	if _, err := defaultGlobCache.GetGlob("a/b/*.json"); err != nil {
+		t.Fatal(err)
+	}
+	if after := filenamesGlobCache.Stats(); after.Hits != before.Hits || after.Misses != before.Misses {
+		t.Error("a lookup in defaultGlobCache should not change filenamesGlobCache's stats")
+	}
+	if _, err := filenamesGlobCache.GetGlob("c/d/*.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	if defaultGlobCache.Stats().Size == 0 {
+		t.Error("defaultGlobCache should have a non-zero size")
+	}
+	if filenamesGlobCache.Stats().Size == 0 {
+		t.Error("filenamesGlobCache should have a non-zero size")
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glob
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gobwas/glob"
+)
+
+// GlobSet compiles a batch of glob patterns into a single matcher that
+// dispatches each lookup to the cheapest applicable bucket instead of
+// testing every pattern in turn. It is meant for the case where the same
+// (possibly large) set of patterns is matched against many filenames, e.g.
+// a mount's exclusion list.
+type GlobSet struct {
+	isCaseSensitive bool
+
+	// Pure literal patterns, e.g. "content/foo.txt".
+	literals map[string]bool
+
+	// Simple "*.ext" patterns, keyed by the extension (with leading dot).
+	// As with the compiled glob, "*" does not cross a "/", so these only
+	// match names without one.
+	extensions map[string]bool
+
+	// Patterns on the form "<literal prefix>/**", grouped by path segment
+	// in a trie so a lookup only walks as far as the name itself goes.
+	prefixes *prefixNode
+
+	// Anything that doesn't fit the buckets above, compiled with
+	// gobwas/glob and matched in order as a last resort.
+	fallback []glob.Glob
+}
+
+type prefixNode struct {
+	children map[string]*prefixNode
+	// isMatch is true if a pattern's prefix ends at this node, meaning
+	// anything below (but not at) this point in the tree matches.
+	isMatch bool
+}
+
+func newPrefixNode() *prefixNode {
+	return &prefixNode{children: make(map[string]*prefixNode)}
+}
+
+func (n *prefixNode) insert(segments []string) {
+	cur := n
+	for _, s := range segments {
+		child, found := cur.children[s]
+		if !found {
+			child = newPrefixNode()
+			cur.children[s] = child
+		}
+		cur = child
+	}
+	cur.isMatch = true
+}
+
+// match reports whether segments has an inserted prefix followed by at
+// least one more segment, mirroring glob.Compile("<prefix>/**").Match,
+// which never matches the literal prefix on its own.
+func (n *prefixNode) match(segments []string) bool {
+	cur := n
+	for _, s := range segments {
+		if cur.isMatch {
+			return true
+		}
+		child, found := cur.children[s]
+		if !found {
+			return false
+		}
+		cur = child
+	}
+	// Every segment was consumed reaching cur, with nothing left over:
+	// that's an exact match of the prefix itself, which "prefix/**"
+	// does not match.
+	return false
+}
+
+// simpleExtPattern returns the extension (with leading dot) that pattern
+// matches if pattern is a plain "*.ext" glob with a single-dot extension
+// (as path.Ext would return it), and false otherwise.
+func simpleExtPattern(pattern string) (string, bool) {
+	if !strings.HasPrefix(pattern, "*.") {
+		return "", false
+	}
+	rest := pattern[1:]
+	if HasGlobChar(rest[1:]) || strings.ContainsAny(rest[1:], "./") {
+		return "", false
+	}
+	return rest, true
+}
+
+// NewGlobSet compiles patterns into a GlobSet. Patterns are matched with
+// '/' as path separator, same as the rest of this package.
+func NewGlobSet(cache *globCache, patterns []string) (*GlobSet, error) {
+	gs := &GlobSet{
+		isCaseSensitive: cache.isCaseSensitive,
+		literals:        make(map[string]bool),
+		extensions:      make(map[string]bool),
+		prefixes:        newPrefixNode(),
+	}
+
+	for _, p := range patterns {
+		p = NormalizePathNoLower(p)
+
+		if !HasGlobChar(p) {
+			gs.literals[gs.normalize(p)] = true
+			continue
+		}
+
+		if ext, ok := simpleExtPattern(p); ok {
+			gs.extensions[gs.normalize(ext)] = true
+			continue
+		}
+
+		if strings.HasSuffix(p, "/**") {
+			prefix := strings.TrimSuffix(p, "/**")
+			if !HasGlobChar(prefix) {
+				gs.prefixes.insert(strings.Split(gs.normalize(prefix), "/"))
+				continue
+			}
+		}
+
+		g, err := cache.GetGlob(p)
+		if err != nil {
+			return nil, err
+		}
+		gs.fallback = append(gs.fallback, g)
+	}
+
+	return gs, nil
+}
+
+func (gs *GlobSet) normalize(s string) string {
+	if !gs.isCaseSensitive {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+// Match returns whether name matches any of the patterns in the set.
+func (gs *GlobSet) Match(name string) bool {
+	if gs == nil {
+		return false
+	}
+
+	name = gs.normalize(strings.Trim(path.Clean("/"+filepath.ToSlash(name)), "/"))
+
+	if gs.literals[name] {
+		return true
+	}
+
+	if ext := path.Ext(name); ext != "" && gs.extensions[ext] && !strings.Contains(name, "/") {
+		return true
+	}
+
+	if gs.prefixes.match(strings.Split(name, "/")) {
+		return true
+	}
+
+	for _, g := range gs.fallback {
+		if g.Match(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NormalizePathNoLower is like NormalizePath but does not lower-case the
+// result; callers that need case-sensitive grouping (e.g. GlobSet, which
+// normalizes case itself where appropriate) use this instead.
+func NormalizePathNoLower(p string) string {
+	return strings.Trim(path.Clean(filepath.ToSlash(p)), "/.")
+}
@@ -0,0 +1,117 @@
+// Copyright 2019 The Hugo Authors. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package glob
+
+import (
+	"testing"
+
+	"github.com/gobwas/glob"
+)
+
+func TestGlobSetBuckets(t *testing.T) {
+	gs, err := NewGlobSet(filenamesGlobCache, []string{
+		"content/about.md", // literal
+		"*.json",           // extension
+		"assets/foo/**",    // prefix trie
+		"a?c.txt",          // fallback
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		want bool
+	}{
+		{"content/about.md", true},
+		{"content/other.md", false},
+		{"site.json", true},
+		{"data/site.json", false},
+		{"data/site.toml", false},
+		{"assets/foo/bar/baz.js", true},
+		{"assets/bar/baz.js", false},
+		{"assets/foo", false},
+		{"abc.txt", true},
+		{"axc.txt", true},
+		{"axxc.txt", false},
+	}
+
+	for _, c := range cases {
+		if got := gs.Match(c.name); got != c.want {
+			t.Errorf("Match(%q) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestGlobSetEmpty(t *testing.T) {
+	gs, err := NewGlobSet(filenamesGlobCache, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gs.Match("anything.md") {
+		t.Error("an empty GlobSet should not match anything")
+	}
+}
+
+// TestGlobSetPrefixDoesNotMatchItself guards against the prefix trie
+// reporting a match for a name identical to a "<prefix>/**" pattern's
+// prefix, which the equivalent compiled glob never does.
+func TestGlobSetPrefixDoesNotMatchItself(t *testing.T) {
+	pattern := "assets/foo/**"
+
+	want, err := glob.Compile(pattern, '/')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gs, err := NewGlobSet(filenamesGlobCache, []string{pattern})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"assets/foo", "assets/foo/bar", "assets", "assets/foobar"}
+	for _, name := range names {
+		if got, wantMatch := gs.Match(name), want.Match(name); got != wantMatch {
+			t.Errorf("GlobSet.Match(%q) = %v, want %v (to agree with glob.Compile)", name, got, wantMatch)
+		}
+	}
+
+	filter, err := NewFilenameFilter(nil, []string{"content/drafts/**"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filter.Match("content/drafts") {
+		t.Error("content/drafts should still be included: it is not itself below content/drafts/")
+	}
+	if filter.Match("content/drafts/foo.md") {
+		t.Error("content/drafts/foo.md should be excluded")
+	}
+}
+
+func TestFilenameFilterUsesGlobSet(t *testing.T) {
+	filter, err := NewFilenameFilter(
+		[]string{"assets/foo/**"},
+		[]string{"*.json"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !filter.Match("assets/foo/bar.js") {
+		t.Error("assets/foo/bar.js should be included")
+	}
+	if filter.Match("data/site.json") {
+		t.Error("data/site.json should be excluded")
+	}
+}